@@ -0,0 +1,134 @@
+// Package amqp provides an AMQP (RabbitMQ) backed linkscraper.Queue, so
+// multiple linkscraper workers can cooperate on a single crawl.
+package amqp
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	rabbitmq "github.com/rabbitmq/amqp091-go"
+	"github.com/vansante/linkscraper"
+	"net/url"
+	"time"
+)
+
+const contentTypeJSON = "application/json"
+
+// Queue is an AMQP-backed linkscraper.Queue. Links are published as JSON
+// and only acked once a worker has finished processing them, so a worker
+// that dies mid-crawl has its links redelivered to another one.
+type Queue struct {
+	conn    *rabbitmq.Connection
+	channel *rabbitmq.Channel
+	queue   string
+
+	deliveries <-chan rabbitmq.Delivery
+}
+
+// Dial connects to the AMQP broker at url and declares (or reuses) the
+// durable queue named queueName. prefetchCount bounds how many
+// unacknowledged links a single worker may hold at once, so work is
+// dispatched fairly across workers sharing the queue.
+func Dial(url, queueName string, prefetchCount int) (*Queue, error) {
+	conn, err := rabbitmq.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing AMQP broker: %v", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error opening AMQP channel: %v", err)
+	}
+	if err := channel.Qos(prefetchCount, 0, false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error setting AMQP prefetch count: %v", err)
+	}
+	if _, err := channel.QueueDeclare(queueName, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error declaring AMQP queue: %v", err)
+	}
+	deliveries, err := channel.Consume(queueName, "", false, false, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error consuming AMQP queue: %v", err)
+	}
+
+	return &Queue{conn: conn, channel: channel, queue: queueName, deliveries: deliveries}, nil
+}
+
+// entry is the JSON payload published for each link. Link.TargetURL is
+// not marshalled with Link, so it travels separately as TargetURL.
+type entry struct {
+	Link      *linkscraper.Link
+	TargetURL string
+	DedupKey  string
+}
+
+// Enqueue publishes link to the queue, tagged with a dedup key hashed
+// from its canonical target URL.
+func (q *Queue) Enqueue(link *linkscraper.Link) error {
+	body, err := json.Marshal(entry{Link: link, TargetURL: link.TargetURL.String(), DedupKey: dedupKey(link)})
+	if err != nil {
+		return fmt.Errorf("error marshalling link: %v", err)
+	}
+
+	return q.channel.Publish("", q.queue, false, false, rabbitmq.Publishing{
+		ContentType:  contentTypeJSON,
+		DeliveryMode: rabbitmq.Persistent,
+		Body:         body,
+	})
+}
+
+// Dequeue waits up to timeout for a delivery. The returned ack func acks
+// the underlying AMQP delivery; it must be called once the link has been
+// fully processed, not before, so a crashed worker's links are redelivered.
+func (q *Queue) Dequeue(timeout time.Duration) (link *linkscraper.Link, ack func(), ok bool, err error) {
+	select {
+	case delivery, open := <-q.deliveries:
+		if !open {
+			return nil, nil, false, nil
+		}
+
+		var e entry
+		if err := json.Unmarshal(delivery.Body, &e); err != nil {
+			delivery.Nack(false, false)
+			return nil, nil, false, fmt.Errorf("error unmarshalling link: %v", err)
+		}
+		target, err := url.Parse(e.TargetURL)
+		if err != nil {
+			delivery.Nack(false, false)
+			return nil, nil, false, fmt.Errorf("error parsing link target URL: %v", err)
+		}
+		e.Link.TargetURL = target
+		return e.Link, func() { delivery.Ack(false) }, true, nil
+	case <-time.After(timeout):
+		return nil, nil, false, nil
+	}
+}
+
+// Len reports the queue's current message count as seen by the broker.
+func (q *Queue) Len() int {
+	state, err := q.channel.QueueInspect(q.queue)
+	if err != nil {
+		return 0
+	}
+	return state.Messages
+}
+
+// Close closes the AMQP channel and connection.
+func (q *Queue) Close() error {
+	if err := q.channel.Close(); err != nil {
+		return err
+	}
+	return q.conn.Close()
+}
+
+// dedupKey hashes link's canonical target URL, so duplicate links
+// published by different workers carry the same key. The key is only
+// published; Dequeue does not read it, and a shared
+// linkscraper.VisitedStore is keyed by the URL string itself.
+func dedupKey(link *linkscraper.Link) string {
+	sum := sha256.Sum256([]byte(link.TargetURL.String()))
+	return fmt.Sprintf("%x", sum)
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"encoding/json"
 	"github.com/vansante/linkscraper"
+	"github.com/vansante/linkscraper/output"
 	"log"
 	"os"
 )
@@ -19,15 +19,15 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+
+	out := output.NewJSONLines(os.Stdout)
+	scraper.SetOutput(out)
+
 	err = scraper.Start()
 	if err != nil {
 		panic(err)
 	}
-
-	data, err := json.MarshalIndent(scraper.Visited(), "", "  ")
-	if err != nil {
+	if err := out.Close(); err != nil {
 		panic(err)
 	}
-
-	log.Print(string(data))
 }
@@ -0,0 +1,33 @@
+package linkscraper
+
+import "sync/atomic"
+
+// InFlightTracker counts links that have been dequeued by some worker but
+// not yet fully processed (i.e. not yet acked). A Queue's own Len() only
+// reports its own ready depth -- for a distributed Queue that excludes
+// messages a worker has dequeued and is still processing -- so
+// runRoutine's idle check needs this count alongside Len() to tell
+// "nothing left to do" apart from "someone is mid-fetch and about to
+// enqueue more links".
+type InFlightTracker interface {
+	// Add adds delta to the shared in-flight count and returns the
+	// count after the change.
+	Add(delta int) (int, error)
+}
+
+// InMemoryInFlightTracker is the default, single-process InFlightTracker,
+// backed by an atomic counter. It is correct for a single process because
+// every worker goroutine sharing it runs in that same process.
+type InMemoryInFlightTracker struct {
+	count int64
+}
+
+// NewInMemoryInFlightTracker creates an InMemoryInFlightTracker starting
+// at zero.
+func NewInMemoryInFlightTracker() *InMemoryInFlightTracker {
+	return &InMemoryInFlightTracker{}
+}
+
+func (t *InMemoryInFlightTracker) Add(delta int) (int, error) {
+	return int(atomic.AddInt64(&t.count, int64(delta))), nil
+}
@@ -0,0 +1,17 @@
+package linkscraper
+
+import "testing"
+
+func TestInMemoryInFlightTrackerAdd(t *testing.T) {
+	tracker := NewInMemoryInFlightTracker()
+
+	if count, err := tracker.Add(1); err != nil || count != 1 {
+		t.Fatalf("Add(1) = %d, %v, want 1, nil", count, err)
+	}
+	if count, err := tracker.Add(1); err != nil || count != 2 {
+		t.Fatalf("Add(1) = %d, %v, want 2, nil", count, err)
+	}
+	if count, err := tracker.Add(-2); err != nil || count != 0 {
+		t.Fatalf("Add(-2) = %d, %v, want 0, nil", count, err)
+	}
+}
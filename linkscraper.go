@@ -1,12 +1,17 @@
 package linkscraper
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"github.com/vansante/linkscraper/robots"
 	"golang.org/x/net/html"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +21,15 @@ const (
 	httpTimeout  = time.Second * 3
 	goroutines   = 20
 	chanCapacity = 10 * 1000
+
+	defaultUserAgent = "linkscraper"
+
+	// dequeueTimeout bounds how long a worker blocks on an empty Queue
+	// before rechecking whether the crawl has gone idle.
+	dequeueTimeout = time.Millisecond * 100
+	// idleTimeout is how long the queue must stay empty, with nothing
+	// in flight, before a worker considers the crawl finished.
+	idleTimeout = time.Second * 2
 )
 
 var (
@@ -25,9 +39,20 @@ var (
 )
 
 type Page struct {
-	URL   *url.URL `json:"-"`
-	Title string
-	Links []*Link
+	URL        *url.URL `json:"-"`
+	Title      string
+	StatusCode int
+	Links      []*Link
+	// RedirectChain holds every URL the request passed through, in
+	// order, when SetFollowRedirects(true) caused it to be followed.
+	RedirectChain []string
+	// RawHeader and RawBody hold the actual HTTP response headers and
+	// body, captured only when an Output is set (see SetOutput), so an
+	// archival Output such as output.WARC can record what was really
+	// fetched instead of reconstructing it from the fields above. A
+	// page fetched via headCheck (see SetFetchRelatedLinks) has no body.
+	RawHeader http.Header `json:"-"`
+	RawBody   []byte      `json:"-"`
 }
 
 type Link struct {
@@ -40,15 +65,39 @@ type Link struct {
 	Anchor     bool
 	Malformed  bool
 	Dead       bool
+	Blocked    bool
+	// Related marks links to assets (images, stylesheets, scripts) found
+	// alongside a page, as opposed to Primary navigation links (<a href>).
+	Related bool
+	// Redirected is set when fetching this link resulted in an HTTP
+	// redirect, either followed (see RedirectChain) or not.
+	Redirected bool
+	// RedirectTarget is the final Location the link redirected to.
+	RedirectTarget string
+	// RedirectChain holds every URL the request passed through, in
+	// order, starting with TargetURL.
+	RedirectChain []string
 }
 
 type LinkChecker struct {
-	client    *http.Client
-	startURL  *url.URL
-	visited   map[string]*Page
-	visitLock sync.RWMutex
-	queue     chan *Link
-	waitGroup sync.WaitGroup
+	client       *http.Client
+	startURL     *url.URL
+	visited      map[string]*Page
+	visitLock    sync.RWMutex
+	visitedStore VisitedStore
+	queue        Queue
+	inFlight     InFlightTracker
+
+	userAgent   string
+	obeyRobots  bool
+	robotsCache *robots.Cache
+
+	scope        Scope
+	fetchRelated bool
+	output       Output
+	normalizer   Normalizer
+
+	redirects *RedirectHandler
 
 	StartPage *Page
 }
@@ -73,72 +122,259 @@ func New(startURL string) (checker *LinkChecker, err error) {
 		return nil, fmt.Errorf("unexpected status code for start URL: %d", resp.StatusCode)
 	}
 
+	redirects := &RedirectHandler{MaxRedirects: defaultMaxRedirects}
+	client.Transport = redirects
+
 	return &LinkChecker{
-		client:   client,
-		startURL: start,
-		visited:  make(map[string]*Page),
-		queue:    make(chan *Link, chanCapacity),
+		client:       client,
+		startURL:     DefaultNormalizer(start),
+		visited:      make(map[string]*Page),
+		visitedStore: NewInMemoryVisitedStore(),
+		queue:        NewChanQueue(chanCapacity),
+		inFlight:     NewInMemoryInFlightTracker(),
+		userAgent:    defaultUserAgent,
+		robotsCache:  robots.NewCache(defaultUserAgent),
+		scope:        SameHostScope{},
+		normalizer:   DefaultNormalizer,
+		redirects:    redirects,
 	}, nil
 }
 
+// Visited returns every page fetched so far, keyed by URL. It is nil once
+// an Output has been set via SetOutput, since pages are then streamed to
+// the Output instead of being kept in memory.
 func (l *LinkChecker) Visited() map[string]*Page {
 	return l.visited
 }
 
+// SetOutput streams discovered pages and links to output as the crawl
+// progresses, and stops retaining fetched pages in memory so Visited()
+// can no longer be used. Call Output.Close once Start returns.
+func (l *LinkChecker) SetOutput(out Output) {
+	l.output = out
+	l.visited = nil
+}
+
+// SetQueue replaces the work queue links are scheduled on. Defaults to a
+// ChanQueue, suitable for a single process; use an AMQP-backed Queue (see
+// the amqp package) to have multiple linkscraper workers cooperate on one
+// crawl.
+func (l *LinkChecker) SetQueue(queue Queue) {
+	l.queue = queue
+}
+
+// SetVisitedStore replaces the store used to decide whether a URL has
+// already been claimed for processing. Defaults to an
+// InMemoryVisitedStore; pair a shared Queue with a Redis-backed
+// VisitedStore (see the redis package) for distributed crawls.
+func (l *LinkChecker) SetVisitedStore(store VisitedStore) {
+	l.visitedStore = store
+}
+
+// SetInFlightTracker replaces the counter used to track links dequeued
+// but not yet acked. Defaults to an InMemoryInFlightTracker; pair a
+// shared Queue with a Redis-backed InFlightTracker (see the redis
+// package) for distributed crawls, since a distributed Queue's Len()
+// alone can't tell idle apart from "another worker is mid-fetch".
+func (l *LinkChecker) SetInFlightTracker(tracker InFlightTracker) {
+	l.inFlight = tracker
+}
+
+// SetUserAgent sets the User-Agent used both for fetching pages and for
+// evaluating robots.txt User-agent groups.
+func (l *LinkChecker) SetUserAgent(userAgent string) {
+	l.userAgent = userAgent
+	l.robotsCache.SetUserAgent(userAgent)
+}
+
+// SetObeyRobots enables or disables honouring robots.txt Disallow/Allow
+// rules and Crawl-delay directives. Disabled by default.
+func (l *LinkChecker) SetObeyRobots(obey bool) {
+	l.obeyRobots = obey
+}
+
+// SetDefaultCrawlDelay sets the delay to use between requests to a host
+// whose robots.txt does not specify a Crawl-delay. Only applies when
+// SetObeyRobots(true) has been called.
+func (l *LinkChecker) SetDefaultCrawlDelay(delay time.Duration) {
+	l.robotsCache.SetDefaultCrawlDelay(delay)
+}
+
+// SetScope sets the Scope used to decide whether a discovered link is
+// internal to the crawl. Defaults to SameHostScope.
+func (l *LinkChecker) SetScope(scope Scope) {
+	l.scope = scope
+}
+
+// SetNormalizer replaces the Normalizer used to canonicalize a resolved
+// link before it is used as the crawl's dedup key. Defaults to
+// DefaultNormalizer.
+func (l *LinkChecker) SetNormalizer(normalizer Normalizer) {
+	l.normalizer = normalizer
+}
+
+// SetFetchRelatedLinks controls whether Related links (images, stylesheets,
+// scripts) are fully fetched like Primary navigation links, or only
+// checked with a HEAD request. Disabled (HEAD-only) by default.
+func (l *LinkChecker) SetFetchRelatedLinks(fetch bool) {
+	l.fetchRelated = fetch
+}
+
+// SetFollowRedirects controls whether redirects are followed. When false
+// (the default), the link that issued the redirect is recorded as
+// Redirected with its RedirectTarget, but never fetched further.
+func (l *LinkChecker) SetFollowRedirects(follow bool) {
+	l.redirects.FollowRedirects = follow
+}
+
+// SetMaxRedirects sets how many redirects to follow for a single request
+// before giving up and recording it as Redirected, when
+// SetFollowRedirects(true) is set. Also bounds redirect loop detection.
+func (l *LinkChecker) SetMaxRedirects(max int) {
+	l.redirects.MaxRedirects = max
+}
+
 func (l *LinkChecker) Start() (err error) {
-	l.waitGroup.Add(1)
-	l.queue <- &Link{
+	l.enqueue(&Link{
 		Dead:      false,
 		Target:    l.startURL.String(),
 		TargetURL: l.startURL,
 		Malformed: false,
 		Internal:  true,
 		Anchor:    false,
-	}
+	})
 
+	var workers sync.WaitGroup
+	workers.Add(goroutines)
 	for i := 0; i < goroutines; i++ {
-		go l.runRoutine()
+		go func() {
+			defer workers.Done()
+			l.runRoutine()
+		}()
 	}
-	l.waitGroup.Wait()
-	close(l.queue)
+	workers.Wait()
 
-	l.StartPage = l.visited[l.startURL.String()]
+	if err := l.queue.Close(); err != nil {
+		return fmt.Errorf("error closing queue: %v", err)
+	}
+
+	l.StartPage = l.visited[l.normalizer(l.startURL).String()]
 
 	return nil
 }
 
+// enqueue schedules link on the Queue. A link enqueued here may be
+// dequeued and acked by a different worker process sharing the same
+// Queue, so runRoutine's idle check relies on the Queue's own shared
+// depth (Len()) rather than any per-process counter of enqueued links.
+func (l *LinkChecker) enqueue(link *Link) {
+	if err := l.queue.Enqueue(link); err != nil {
+		log.Printf("Error enqueueing link: %s |> %v", link.Target, err)
+	}
+}
+
+// runRoutine pulls links off the Queue until the Queue's shared depth
+// and the shared in-flight count have both been zero for idleTimeout --
+// replacing a sync.WaitGroup, which can't be shared across a distributed
+// Queue's workers. Len() alone isn't enough: a distributed Queue's
+// broker-reported depth excludes a link another worker process has
+// dequeued and is still processing, which is exactly what
+// InFlightTracker -- shared across every worker, unlike a per-process
+// counter -- covers.
 func (l *LinkChecker) runRoutine() {
+	var idleSince time.Time
 	for {
-		link, ok := <-l.queue
+		link, ack, ok, err := l.queue.Dequeue(dequeueTimeout)
+		if err != nil {
+			log.Printf("Error dequeuing link: %v", err)
+			continue
+		}
 		if !ok {
-			return // Were done!
+			inFlight, err := l.inFlight.Add(0)
+			if err != nil {
+				log.Printf("Error reading in-flight count: %v", err)
+			}
+			if err != nil || inFlight > 0 || l.queue.Len() > 0 {
+				idleSince = time.Time{}
+				continue
+			}
+			if idleSince.IsZero() {
+				idleSince = time.Now()
+			} else if time.Since(idleSince) >= idleTimeout {
+				return
+			}
+			continue
 		}
-		l.processLink(link)
+		idleSince = time.Time{}
 
-		l.waitGroup.Done()
+		if _, err := l.inFlight.Add(1); err != nil {
+			log.Printf("Error incrementing in-flight count: %v", err)
+		}
+		l.processLink(link)
+		if _, err := l.inFlight.Add(-1); err != nil {
+			log.Printf("Error decrementing in-flight count: %v", err)
+		}
+		ack()
 	}
 }
 
 func (l *LinkChecker) processLink(link *Link) {
-	l.visitLock.RLock()
-	page := l.visited[link.TargetURL.String()]
-	l.visitLock.RUnlock()
+	key := l.normalizer(link.TargetURL).String()
+
+	isNew, err := l.visitedStore.MarkVisited(key)
+	if err != nil {
+		log.Printf("Error marking link visited: %s |> %v", link.Target, err)
+		return
+	}
+	if !isNew {
+		l.visitLock.RLock()
+		page := l.visited[key]
+		l.visitLock.RUnlock()
 
-	if page != nil {
 		link.TargetPage = page
 		link.Dead = false
 		return
 	}
 
-	statusCode, page, err := l.visitPage(link.TargetURL)
+	var statusCode int
+	var page *Page
+	if link.Related && !l.fetchRelated {
+		statusCode, page, err = l.headCheck(link.TargetURL)
+	} else {
+		statusCode, page, err = l.visitPage(link.TargetURL)
+	}
+	var redirErr *RedirectError
+	if errors.As(err, &redirErr) {
+		link.Redirected = true
+		link.RedirectTarget = redirErr.Location.String()
+		link.RedirectChain = redirErr.Chain
+		link.Dead = redirErr.Loop
+		link.Internal = l.isInternal(redirErr.Location)
+		return
+	}
 	if err != nil {
 		log.Printf("Error visiting page: %s |> %v", link.Target, err)
 		return
 	}
 
-	l.visitLock.Lock()
-	l.visited[link.TargetURL.String()] = page
-	l.visitLock.Unlock()
+	if len(page.RedirectChain) > 0 {
+		link.Redirected = true
+		link.RedirectChain = page.RedirectChain
+		link.RedirectTarget = page.RedirectChain[len(page.RedirectChain)-1]
+		link.Internal = l.isInternal(page.URL)
+	}
+
+	if l.output == nil {
+		l.visitLock.Lock()
+		l.visited[key] = page
+		l.visitLock.Unlock()
+	}
+
+	if l.output != nil {
+		if err := l.output.OnPage(page); err != nil {
+			log.Printf("Error writing page to output: %s |> %v", link.Target, err)
+		}
+	}
 
 	link.TargetPage = page
 	link.Dead = statusCode < 200 || statusCode > 299
@@ -148,7 +384,17 @@ func (l *LinkChecker) processLink(link *Link) {
 func (l *LinkChecker) visitPage(curURL *url.URL) (statusCode int, page *Page, err error) {
 	//log.Printf("Visiting URL: %s", curURL.String())
 
-	resp, err := l.client.Get(curURL.String())
+	if l.obeyRobots {
+		l.robotsCache.Wait(curURL)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, curURL.String(), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("User-Agent", l.userAgent)
+
+	resp, err := l.client.Do(req)
 	if isTimeout(err) {
 		return 0, NonExistingPage, nil
 
@@ -164,10 +410,21 @@ func (l *LinkChecker) visitPage(curURL *url.URL) (statusCode int, page *Page, er
 	}
 
 	page = &Page{
-		URL: curURL,
+		URL:        curURL,
+		StatusCode: resp.StatusCode,
+	}
+	applyRedirectChain(page, resp)
+
+	// Only tee the body into memory when something will actually consume
+	// it, so a plain crawl without an Output doesn't pay for buffering
+	// every page it visits.
+	var rawBody bytes.Buffer
+	var body io.Reader = resp.Body
+	if l.output != nil {
+		body = io.TeeReader(resp.Body, &rawBody)
 	}
 
-	tokenizer := html.NewTokenizer(resp.Body)
+	tokenizer := html.NewTokenizer(body)
 	prevStartToken := tokenizer.Token()
 	var lastLink *Link
 	for {
@@ -175,15 +432,27 @@ func (l *LinkChecker) visitPage(curURL *url.URL) (statusCode int, page *Page, er
 		token := tokenizer.Token()
 		switch tokenType {
 		case html.ErrorToken:
-			return //were done
-		case html.StartTagToken:
+			if l.output != nil {
+				page.RawHeader = resp.Header
+				page.RawBody = rawBody.Bytes()
+			}
+			return resp.StatusCode, page, nil //were done
+		case html.StartTagToken, html.SelfClosingTagToken:
 			prevStartToken = token
 			switch strings.ToLower(token.Data) {
 			case "a":
-				lastLink = l.processAnchor(page, token)
+				lastLink = l.processLinkAttr(page, token, "href", false)
 				if lastLink != nil {
 					page.Links = append(page.Links, lastLink)
 				}
+			case "img", "script":
+				if link := l.processLinkAttr(page, token, "src", true); link != nil {
+					page.Links = append(page.Links, link)
+				}
+			case "link":
+				if link := l.processLinkAttr(page, token, "href", true); link != nil {
+					page.Links = append(page.Links, link)
+				}
 			}
 		case html.TextToken:
 			switch strings.ToLower(prevStartToken.Data) {
@@ -193,52 +462,142 @@ func (l *LinkChecker) visitPage(curURL *url.URL) (statusCode int, page *Page, er
 				if lastLink != nil {
 					lastLink.Title += strings.TrimSpace(token.String())
 				}
+			case "style":
+				for _, ref := range extractCSSURLs(token.String()) {
+					if link := l.processRelatedTarget(page, ref, true); link != nil {
+						page.Links = append(page.Links, link)
+					}
+				}
 			}
 		}
 	}
+}
+
+// headCheck checks curURL with a HEAD request instead of fetching and
+// parsing the full page, used for Related links when fetching them fully
+// hasn't been enabled via SetFetchRelatedLinks.
+func (l *LinkChecker) headCheck(curURL *url.URL) (statusCode int, page *Page, err error) {
+	if l.obeyRobots {
+		l.robotsCache.Wait(curURL)
+	}
+
+	req, err := http.NewRequest(http.MethodHead, curURL.String(), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("User-Agent", l.userAgent)
+
+	resp, err := l.client.Do(req)
+	if isTimeout(err) {
+		return 0, NonExistingPage, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, NonExistingPage, nil
+	}
+	page = &Page{URL: curURL, StatusCode: resp.StatusCode}
+	applyRedirectChain(page, resp)
+	if l.output != nil {
+		page.RawHeader = resp.Header // A HEAD response never has a body.
+	}
 	return resp.StatusCode, page, nil
 }
 
-func (l *LinkChecker) processAnchor(page *Page, anchor html.Token) *Link {
-	for i := range anchor.Attr {
-		if strings.ToLower(anchor.Attr[i].Key) != "href" {
+// applyRedirectChain records the chain RedirectHandler followed (if any)
+// on page, updating page.URL to the final URL the chain resolved to.
+func applyRedirectChain(page *Page, resp *http.Response) {
+	raw := resp.Header.Get(redirectChainHeader)
+	if raw == "" {
+		return
+	}
+	page.RedirectChain = strings.Split(raw, ",")
+
+	if final, err := url.Parse(page.RedirectChain[len(page.RedirectChain)-1]); err == nil {
+		page.URL = final
+	}
+}
+
+// processLinkAttr reads the given attribute off a tag and, if present,
+// turns it into a Link, queuing it for a visit when it is in scope.
+// related marks asset links (img/link/script) as opposed to <a> navigation.
+func (l *LinkChecker) processLinkAttr(page *Page, tag html.Token, attrKey string, related bool) *Link {
+	for i := range tag.Attr {
+		if strings.ToLower(tag.Attr[i].Key) != attrKey {
 			continue
 		}
+		return l.processRelatedTarget(page, tag.Attr[i].Val, related)
+	}
+	return nil
+}
 
-		link := &Link{
-			Page:   page,
-			Target: anchor.Attr[i].Val,
-		}
+// processRelatedTarget turns a raw target string into a Link, queuing it
+// for a visit when it is in scope.
+func (l *LinkChecker) processRelatedTarget(page *Page, target string, related bool) *Link {
+	link := &Link{
+		Page:    page,
+		Target:  target,
+		Related: related,
+	}
 
-		if strings.TrimSpace(anchor.Attr[i].Val) == "" {
-			link.Malformed = true
-			return link
-		}
-		if strings.HasPrefix(strings.TrimSpace(anchor.Attr[i].Val), "#") {
-			link.Internal = true
-			link.Anchor = true
-			return link
-		}
-		var err error
-		link.TargetURL, err = url.Parse(anchor.Attr[i].Val)
-		if err != nil {
-			link.Malformed = true
-			log.Printf("Found an unparsable link: %s (%v)", anchor.Attr[i].Val, err)
-			return link
-		}
+	if l.output != nil {
+		defer func() {
+			if err := l.output.OnLink(link); err != nil {
+				log.Printf("Error writing link to output: %s |> %v", target, err)
+			}
+		}()
+	}
 
-		link.Internal = l.isInternal(link.TargetURL)
-		if link.Internal {
-			l.waitGroup.Add(1)
-			l.queue <- link
-		}
+	if strings.TrimSpace(target) == "" {
+		link.Malformed = true
 		return link
 	}
-	return nil
+	if strings.HasPrefix(strings.TrimSpace(target), "#") {
+		link.Internal = true
+		link.Anchor = true
+		return link
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		link.Malformed = true
+		log.Printf("Found an unparsable link: %s (%v)", target, err)
+		return link
+	}
+	if page.URL != nil {
+		parsed = page.URL.ResolveReference(parsed)
+	}
+	link.TargetURL = l.normalizer(parsed)
+
+	link.Internal = l.isInternal(link.TargetURL)
+	if link.Internal {
+		if l.obeyRobots && !l.robotsCache.Allowed(link.TargetURL) {
+			link.Blocked = true
+			return link
+		}
+		l.enqueue(link)
+	}
+	return link
 }
 
 func (l *LinkChecker) isInternal(URL *url.URL) bool {
-	return l.startURL.Host == URL.Host
+	return l.scope.InScope(l.startURL, URL)
+}
+
+// cssURLPattern matches url(...) references in inline CSS, e.g.
+// background: url("/img/bg.png").
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// extractCSSURLs returns the targets of every url(...) reference in css.
+func extractCSSURLs(css string) []string {
+	matches := cssURLPattern.FindAllStringSubmatch(css, -1)
+	urls := make([]string, 0, len(matches))
+	for _, match := range matches {
+		urls = append(urls, strings.TrimSpace(match[1]))
+	}
+	return urls
 }
 
 func isTimeout(err error) bool {
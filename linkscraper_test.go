@@ -0,0 +1,39 @@
+package linkscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestVisitPageReturnsFetchedStatusCode guards against visitPage's bare
+// "return" inside the tokenizer loop silently sending back statusCode's
+// zero value instead of the page's actual HTTP status.
+func TestVisitPageReturnsFetchedStatusCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head><title>ok</title></head><body></body></html>"))
+	}))
+	defer server.Close()
+
+	checker, err := New(server.URL)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() error: %v", err)
+	}
+
+	statusCode, page, err := checker.visitPage(target)
+	if err != nil {
+		t.Fatalf("visitPage() error: %v", err)
+	}
+	if statusCode != http.StatusOK {
+		t.Errorf("statusCode = %d, want %d", statusCode, http.StatusOK)
+	}
+	if page.StatusCode != http.StatusOK {
+		t.Errorf("page.StatusCode = %d, want %d", page.StatusCode, http.StatusOK)
+	}
+}
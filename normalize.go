@@ -0,0 +1,92 @@
+package linkscraper
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Normalizer canonicalizes a resolved link target before it is used as
+// the crawl's dedup key, so equivalent URLs (differing only in host
+// case, default port, fragment, query order, ...) collapse to a single
+// Link instead of being fetched once per variant.
+type Normalizer func(target *url.URL) *url.URL
+
+// trackingParams lists query parameters commonly added by marketing
+// tools that don't change what a page is; DefaultNormalizer strips them.
+var trackingParams = map[string]bool{
+	"utm_source":   true,
+	"utm_medium":   true,
+	"utm_campaign": true,
+	"utm_term":     true,
+	"utm_content":  true,
+	"fbclid":       true,
+	"gclid":        true,
+}
+
+// DefaultNormalizer lower-cases the host, strips default ports and
+// fragments, cleans "." / ".." path segments, drops tracking query
+// parameters and sorts the rest.
+func DefaultNormalizer(target *url.URL) *url.URL {
+	normalized := *target
+	normalized.Host = strings.ToLower(stripDefaultPort(normalized.Scheme, normalized.Host))
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+
+	if normalized.Path != "" {
+		cleaned := path.Clean(normalized.Path)
+		if strings.HasSuffix(normalized.Path, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		normalized.Path = cleaned
+	}
+
+	query := normalized.Query()
+	for param := range query {
+		if trackingParams[strings.ToLower(param)] {
+			query.Del(param)
+		}
+	}
+	normalized.RawQuery = sortedQuery(query)
+
+	return &normalized
+}
+
+// stripDefaultPort removes a port from host when it is the scheme's
+// default (:80 for http, :443 for https).
+func stripDefaultPort(scheme, host string) string {
+	switch {
+	case scheme == "http" && strings.HasSuffix(host, ":80"):
+		return strings.TrimSuffix(host, ":80")
+	case scheme == "https" && strings.HasSuffix(host, ":443"):
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+// sortedQuery re-encodes query with its parameters, and each parameter's
+// values, in sorted order, so e.g. "?b=1&a=2" and "?a=2&b=1" normalize to
+// the same string.
+func sortedQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(key))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(value))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package linkscraper
+
+import "testing"
+
+func TestDefaultNormalizer(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "lower-cases the host",
+			in:   "http://EXAMPLE.com/Path",
+			want: "http://example.com/Path",
+		},
+		{
+			name: "strips the default port for the scheme",
+			in:   "http://example.com:80/",
+			want: "http://example.com/",
+		},
+		{
+			name: "keeps a non-default port",
+			in:   "http://example.com:8080/",
+			want: "http://example.com:8080/",
+		},
+		{
+			name: "strips fragments",
+			in:   "http://example.com/#section",
+			want: "http://example.com/",
+		},
+		{
+			name: "cleans . and .. path segments",
+			in:   "http://example.com/a/../b/./c",
+			want: "http://example.com/b/c",
+		},
+		{
+			name: "drops tracking query parameters",
+			in:   "http://example.com/?utm_source=newsletter&id=1",
+			want: "http://example.com/?id=1",
+		},
+		{
+			name: "sorts remaining query parameters",
+			in:   "http://example.com/?b=2&a=1",
+			want: "http://example.com/?a=1&b=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := mustParseURL(t, tt.in)
+			got := DefaultNormalizer(u).String()
+			if got != tt.want {
+				t.Errorf("DefaultNormalizer(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package linkscraper
+
+// Output receives pages and links as they are discovered, so a crawl's
+// results can be streamed to disk instead of being held entirely in
+// memory via Visited(). Implementations must be safe for concurrent use.
+type Output interface {
+	// OnPage is called once a page has finished being fetched and parsed.
+	OnPage(page *Page) error
+	// OnLink is called for every link discovered on a page, including
+	// anchors, malformed, blocked and out-of-scope ones.
+	OnLink(link *Link) error
+	// Close flushes and releases any resources held by the Output.
+	Close() error
+}
@@ -0,0 +1,41 @@
+// Package output provides linkscraper.Output implementations that stream
+// crawl results to disk instead of holding the whole page graph in memory.
+package output
+
+import (
+	"encoding/json"
+	"github.com/vansante/linkscraper"
+	"io"
+	"sync"
+)
+
+// JSONLines writes each finished page as its own JSON object followed by
+// a newline, so a large crawl's results can be processed as they arrive
+// instead of being read back as one big JSON document.
+type JSONLines struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+// NewJSONLines creates a JSONLines writer that appends to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{enc: json.NewEncoder(w)}
+}
+
+// OnPage writes page as a single JSON line.
+func (j *JSONLines) OnPage(page *linkscraper.Page) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.enc.Encode(page)
+}
+
+// OnLink is a no-op; links are reported as part of their Page's Links.
+func (j *JSONLines) OnLink(link *linkscraper.Link) error {
+	return nil
+}
+
+// Close is a no-op; JSONLines does not own the underlying writer.
+func (j *JSONLines) Close() error {
+	return nil
+}
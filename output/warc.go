@@ -0,0 +1,122 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"github.com/vansante/linkscraper"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const warcVersion = "WARC/1.1"
+
+// WARC writes each page as a pair of WARC 1.1 records (request, response),
+// each its own gzip member, suitable for long-term archival crawls. The
+// response record is built from Page.RawHeader/RawBody, so a LinkChecker
+// must have had SetOutput called on it for those fields to be populated.
+type WARC struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// NewWARC creates a WARC writer that appends gzip members to w.
+func NewWARC(w io.Writer) *WARC {
+	return &WARC{w: w}
+}
+
+// OnPage writes a request and response record for page. Pages that were
+// never actually fetched (e.g. linkscraper.NonExistingPage, returned for
+// dead links and timeouts) have no URL and are skipped, since there is
+// nothing to archive for them.
+func (warc *WARC) OnPage(page *linkscraper.Page) error {
+	if page.URL == nil {
+		return nil
+	}
+
+	warc.lock.Lock()
+	defer warc.lock.Unlock()
+
+	targetURI := page.URL.String()
+	date := time.Now().UTC().Format(time.RFC3339)
+
+	request := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: linkscraper\r\n\r\n",
+		page.URL.RequestURI(), page.URL.Host)
+	if err := warc.writeRecord("request", targetURI, date, []byte(request)); err != nil {
+		return err
+	}
+
+	status := page.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	var response bytes.Buffer
+	fmt.Fprintf(&response, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	if page.RawHeader != nil {
+		if err := page.RawHeader.Write(&response); err != nil {
+			return err
+		}
+	}
+	response.WriteString("\r\n")
+	response.Write(page.RawBody)
+
+	return warc.writeRecord("response", targetURI, date, response.Bytes())
+}
+
+// OnLink is a no-op; links are reported as part of their Page's response.
+func (warc *WARC) OnLink(link *linkscraper.Link) error {
+	return nil
+}
+
+// Close is a no-op; WARC does not own the underlying writer.
+func (warc *WARC) Close() error {
+	return nil
+}
+
+// writeRecord writes a single gzip-compressed WARC record of the given
+// WARC-Type to warc.w.
+func (warc *WARC) writeRecord(recordType, targetURI, date string, content []byte) error {
+	id, err := newWARCRecordID()
+	if err != nil {
+		return err
+	}
+
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "%s\r\n", warcVersion)
+	fmt.Fprintf(&header, "WARC-Type: %s\r\n", recordType)
+	fmt.Fprintf(&header, "WARC-Record-ID: %s\r\n", id)
+	fmt.Fprintf(&header, "WARC-Target-URI: %s\r\n", targetURI)
+	fmt.Fprintf(&header, "WARC-Date: %s\r\n", date)
+	fmt.Fprintf(&header, "Content-Type: application/http; msgtype=%s\r\n", recordType)
+	fmt.Fprintf(&header, "Content-Length: %d\r\n\r\n", len(content))
+
+	gz := gzip.NewWriter(warc.w)
+	if _, err := gz.Write(header.Bytes()); err != nil {
+		return err
+	}
+	if _, err := gz.Write(content); err != nil {
+		return err
+	}
+	if _, err := gz.Write([]byte("\r\n\r\n")); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// newWARCRecordID generates a random (version 4) UUID formatted as a WARC
+// record ID URN, e.g. "<urn:uuid:...>".
+func newWARCRecordID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4.
+	b[8] = (b[8] & 0x3f) | 0x80 // Variant 10.
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>",
+		b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
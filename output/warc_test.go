@@ -0,0 +1,22 @@
+package output
+
+import (
+	"bytes"
+	"github.com/vansante/linkscraper"
+	"testing"
+)
+
+// TestWARCOnPageSkipsPageWithoutURL guards against a nil-pointer panic on
+// linkscraper.NonExistingPage, which is reported via OnPage for dead
+// links and timeouts but never has a URL set.
+func TestWARCOnPageSkipsPageWithoutURL(t *testing.T) {
+	var buf bytes.Buffer
+	warc := NewWARC(&buf)
+
+	if err := warc.OnPage(linkscraper.NonExistingPage); err != nil {
+		t.Fatalf("OnPage() error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("OnPage() wrote %d bytes for a page without a URL, want 0", buf.Len())
+	}
+}
@@ -0,0 +1,60 @@
+package linkscraper
+
+import "time"
+
+// Queue decouples LinkChecker from a specific work-queue implementation,
+// so multiple linkscraper workers -- potentially running in separate
+// processes -- can cooperate on a single crawl by sharing a Queue and a
+// VisitedStore.
+type Queue interface {
+	// Enqueue schedules link to be processed.
+	Enqueue(link *Link) error
+	// Dequeue waits up to timeout for a link to become available. ok is
+	// false if none arrived within timeout or the queue was closed, in
+	// which case the caller should re-check queue depth before retrying.
+	Dequeue(timeout time.Duration) (link *Link, ack func(), ok bool, err error)
+	// Len reports the number of links currently queued, used to detect
+	// an idle crawl.
+	Len() int
+	// Close releases the queue's resources. Enqueue/Dequeue must not be
+	// called afterwards.
+	Close() error
+}
+
+// ChanQueue is the default, single-process Queue, backed by a buffered Go
+// channel. Its ack func is a no-op, since an in-process channel send
+// already guarantees delivery.
+type ChanQueue struct {
+	ch chan *Link
+}
+
+// NewChanQueue creates a ChanQueue with room for capacity queued links.
+func NewChanQueue(capacity int) *ChanQueue {
+	return &ChanQueue{ch: make(chan *Link, capacity)}
+}
+
+func (q *ChanQueue) Enqueue(link *Link) error {
+	q.ch <- link
+	return nil
+}
+
+func (q *ChanQueue) Dequeue(timeout time.Duration) (link *Link, ack func(), ok bool, err error) {
+	select {
+	case link, ok := <-q.ch:
+		if !ok {
+			return nil, nil, false, nil
+		}
+		return link, func() {}, true, nil
+	case <-time.After(timeout):
+		return nil, nil, false, nil
+	}
+}
+
+func (q *ChanQueue) Len() int {
+	return len(q.ch)
+}
+
+func (q *ChanQueue) Close() error {
+	close(q.ch)
+	return nil
+}
@@ -0,0 +1,112 @@
+package linkscraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultMaxRedirects = 10
+
+	// redirectChainHeader carries the followed redirect chain on the final
+	// response when RedirectHandler.FollowRedirects is enabled, since
+	// http.RoundTripper has no other channel to report out-of-band data.
+	redirectChainHeader = "X-Linkscraper-Redirect-Chain"
+)
+
+// RedirectError is returned by RedirectHandler instead of following a
+// redirect, carrying the location it would have followed to.
+type RedirectError struct {
+	Location   *url.URL
+	StatusCode int
+	Chain      []string
+	Loop       bool
+}
+
+func (e *RedirectError) Error() string {
+	if e.Loop {
+		return fmt.Sprintf("redirect loop detected at %s (status %d)", e.Location, e.StatusCode)
+	}
+	return fmt.Sprintf("redirected to %s (status %d)", e.Location, e.StatusCode)
+}
+
+// RedirectHandler is an http.RoundTripper that takes over redirect
+// handling from http.Client, so a crawler can tell 404s apart from pages
+// that are merely redirected. When FollowRedirects is false (the
+// default), the first redirect response returns a *RedirectError
+// carrying its Location instead of a *http.Response. When true, it
+// follows redirects itself (up to MaxRedirects) and records the chain it
+// took in the final response's redirectChainHeader.
+type RedirectHandler struct {
+	Transport       http.RoundTripper
+	FollowRedirects bool
+	MaxRedirects    int
+}
+
+func (r *RedirectHandler) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	maxRedirects := r.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	chain := []string{req.URL.String()}
+	seen := map[string]bool{req.URL.String(): true}
+	cur := req
+
+	for {
+		resp, err := transport.RoundTrip(cur)
+		if err != nil {
+			return nil, err
+		}
+		if !isRedirectStatus(resp.StatusCode) {
+			if len(chain) > 1 {
+				resp.Header.Set(redirectChainHeader, strings.Join(chain, ","))
+			}
+			return resp, nil
+		}
+
+		location := resp.Header.Get("Location")
+		resp.Body.Close()
+		if location == "" {
+			return resp, nil
+		}
+		next, err := cur.URL.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("linkscraper: invalid redirect location %q: %v", location, err)
+		}
+
+		loop := seen[next.String()]
+		seen[next.String()] = true
+		chain = append(chain, next.String())
+
+		if loop || !r.FollowRedirects || len(chain)-1 > maxRedirects {
+			return nil, &RedirectError{
+				Location:   next,
+				StatusCode: resp.StatusCode,
+				Chain:      chain,
+				Loop:       loop,
+			}
+		}
+
+		redirected := cur.Clone(cur.Context())
+		redirected.URL = next
+		redirected.Host = ""
+		cur = redirected
+	}
+}
+
+// isRedirectStatus reports whether statusCode is an HTTP redirect.
+func isRedirectStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package redis
+
+import (
+	"context"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// InFlightTracker is a Redis-backed linkscraper.InFlightTracker, so the
+// in-flight count is shared across every worker process sharing an AMQP
+// queue instead of being tracked per process.
+type InFlightTracker struct {
+	client *goredis.Client
+	key    string
+}
+
+// NewInFlightTracker creates an InFlightTracker using client, storing its
+// count under key so one Redis instance can back more than one crawl.
+func NewInFlightTracker(client *goredis.Client, key string) *InFlightTracker {
+	return &InFlightTracker{client: client, key: key}
+}
+
+// Add uses INCRBY so concurrent workers adjusting the count never race.
+func (t *InFlightTracker) Add(delta int) (int, error) {
+	count, err := t.client.IncrBy(context.Background(), t.key, int64(delta)).Result()
+	return int(count), err
+}
@@ -0,0 +1,28 @@
+// Package redis provides a Redis-backed linkscraper.VisitedStore and
+// linkscraper.InFlightTracker, so multiple linkscraper workers sharing an
+// AMQP queue agree on which URLs have already been claimed for
+// processing and on when the crawl has actually gone idle.
+package redis
+
+import (
+	"context"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// VisitedStore is a Redis-backed linkscraper.VisitedStore.
+type VisitedStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// NewVisitedStore creates a VisitedStore using client, namespacing its
+// keys under prefix so one Redis instance can back more than one crawl.
+func NewVisitedStore(client *goredis.Client, prefix string) *VisitedStore {
+	return &VisitedStore{client: client, prefix: prefix}
+}
+
+// MarkVisited uses SETNX so only the first worker to claim key gets true
+// back; it never expires, matching the lifetime of a single crawl.
+func (s *VisitedStore) MarkVisited(key string) (bool, error) {
+	return s.client.SetNX(context.Background(), s.prefix+key, 1, 0).Result()
+}
@@ -0,0 +1,287 @@
+// Package robots fetches, parses and caches robots.txt files so a crawler
+// can honour Disallow/Allow rules and per-host Crawl-delay directives.
+package robots
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fetchTimeout = time.Second * 3
+
+// group holds the rules that apply to one or more User-agent tokens within
+// a single robots.txt record.
+type group struct {
+	agents     []string
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// matches reports whether the group applies to the given User-Agent.
+func (g group) matches(userAgent string) bool {
+	for _, agent := range g.agents {
+		if agent == "*" {
+			return true
+		}
+		if strings.EqualFold(agent, userAgent) {
+			return true
+		}
+	}
+	return false
+}
+
+// host holds the parsed rules and crawl-delay state for a single host.
+type host struct {
+	groups     []group
+	crawlDelay time.Duration
+
+	lock        sync.Mutex
+	lastRequest time.Time
+
+	// fetchOnce guards the robots.txt fetch that populates groups and
+	// crawlDelay above, so concurrent callers for the same host share a
+	// single fetch instead of racing or serializing behind Cache.lock.
+	fetchOnce sync.Once
+}
+
+// selectGroup returns the group in groups that most specifically applies
+// to userAgent: a group explicitly naming userAgent, falling back to a
+// `*` group only when no group names it by name, or nil if neither kind
+// of group is present.
+func selectGroup(groups []group, userAgent string) *group {
+	var named, wildcard *group
+	for i := range groups {
+		g := &groups[i]
+		if !g.matches(userAgent) {
+			continue
+		}
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = g
+			} else {
+				named = g
+			}
+		}
+	}
+
+	if named != nil {
+		return named
+	}
+	return wildcard
+}
+
+// allowed reports whether path may be fetched by userAgent, using the most
+// specific matching group. The `*` group is only used when no group
+// explicitly names userAgent.
+func (h *host) allowed(path, userAgent string) bool {
+	g := selectGroup(h.groups, userAgent)
+	if g == nil {
+		return true
+	}
+
+	bestLen := -1
+	allowed := true
+	for _, rule := range g.disallow {
+		if rule == "" {
+			continue // An empty Disallow means "allow everything".
+		}
+		if strings.HasPrefix(path, rule) && len(rule) > bestLen {
+			bestLen, allowed = len(rule), false
+		}
+	}
+	for _, rule := range g.allow {
+		if rule == "" {
+			continue
+		}
+		if strings.HasPrefix(path, rule) && len(rule) > bestLen {
+			bestLen, allowed = len(rule), true
+		}
+	}
+	return allowed
+}
+
+// wait blocks until delay has elapsed since the previous request to this
+// host, then records the new request time.
+func (h *host) wait(delay time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if delay <= 0 {
+		h.lastRequest = time.Now()
+		return
+	}
+
+	if since := time.Since(h.lastRequest); since < delay {
+		time.Sleep(delay - since)
+	}
+	h.lastRequest = time.Now()
+}
+
+// Cache fetches, parses and caches robots.txt files per host, and enforces
+// Crawl-delay between requests to the same host.
+type Cache struct {
+	client            *http.Client
+	userAgent         string
+	defaultCrawlDelay time.Duration
+
+	lock  sync.Mutex
+	hosts map[string]*host
+}
+
+// NewCache creates a Cache that evaluates robots.txt rules for userAgent.
+func NewCache(userAgent string) *Cache {
+	return &Cache{
+		client:    &http.Client{Timeout: fetchTimeout},
+		userAgent: userAgent,
+		hosts:     make(map[string]*host),
+	}
+}
+
+// SetDefaultCrawlDelay sets the delay to use for hosts whose robots.txt
+// does not specify a Crawl-delay.
+func (c *Cache) SetDefaultCrawlDelay(delay time.Duration) {
+	c.defaultCrawlDelay = delay
+}
+
+// SetUserAgent changes the User-Agent used to evaluate robots.txt
+// User-agent groups. Allowed applies it to every host, including hosts
+// already cached, but a cached host's Crawl-delay, chosen when its
+// robots.txt was fetched, is not re-evaluated.
+func (c *Cache) SetUserAgent(userAgent string) {
+	c.userAgent = userAgent
+}
+
+// Allowed reports whether target may be fetched, fetching and caching the
+// target's robots.txt on first use. Hosts whose robots.txt cannot be
+// fetched are treated as allowing everything.
+func (c *Cache) Allowed(target *url.URL) bool {
+	h := c.hostFor(target)
+
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+
+	return h.allowed(path, c.userAgent)
+}
+
+// Wait blocks as needed to respect target's host's Crawl-delay.
+func (c *Cache) Wait(target *url.URL) {
+	h := c.hostFor(target)
+
+	delay := h.crawlDelay
+	if delay <= 0 {
+		delay = c.defaultCrawlDelay
+	}
+	h.wait(delay)
+}
+
+// hostFor returns the (possibly newly fetched) host entry for target's
+// host. The map lookup is guarded by c.lock, but the robots.txt fetch
+// itself happens on the returned host's own fetchOnce, so a slow fetch
+// for one host never blocks Allowed/Wait calls for any other host.
+func (c *Cache) hostFor(target *url.URL) *host {
+	c.lock.Lock()
+	h := c.hosts[target.Host]
+	if h == nil {
+		h = &host{}
+		c.hosts[target.Host] = h
+	}
+	c.lock.Unlock()
+
+	h.fetchOnce.Do(func() {
+		c.fetch(h, target)
+	})
+	return h
+}
+
+// fetch retrieves and parses the robots.txt for target's host, populating h.
+func (c *Cache) fetch(h *host, target *url.URL) {
+	robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+	resp, err := c.client.Get(robotsURL.String())
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return
+	}
+
+	h.groups = parse(resp.Body)
+	if g := selectGroup(h.groups, c.userAgent); g != nil {
+		h.crawlDelay = g.crawlDelay
+	}
+}
+
+// parse reads a robots.txt document and returns its User-agent groups.
+func parse(r interface{ Read([]byte) (int, error) }) []group {
+	var groups []group
+	var cur *group
+	sawRule := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+
+		field, value, ok := splitDirective(line)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(field) {
+		case "user-agent":
+			if cur == nil || sawRule {
+				groups = append(groups, group{})
+				cur = &groups[len(groups)-1]
+				sawRule = false
+			}
+			cur.agents = append(cur.agents, value)
+		case "disallow":
+			if cur == nil {
+				continue
+			}
+			cur.disallow = append(cur.disallow, value)
+			sawRule = true
+		case "allow":
+			if cur == nil {
+				continue
+			}
+			cur.allow = append(cur.allow, value)
+			sawRule = true
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				cur.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+			sawRule = true
+		}
+	}
+	return groups
+}
+
+// splitDirective splits a "Field: value" robots.txt line.
+func splitDirective(line string) (field, value string, ok bool) {
+	idx := strings.IndexByte(line, ':')
+	if idx < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:]), true
+}
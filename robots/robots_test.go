@@ -0,0 +1,84 @@
+package robots
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestSelectGroup locks in the named-over-wildcard precedence that both
+// allowed() and Cache.fetch() rely on.
+func TestSelectGroup(t *testing.T) {
+	named := group{agents: []string{"linkscraper"}, crawlDelay: 5 * time.Second}
+	wildcard := group{agents: []string{"*"}, crawlDelay: 100 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		groups    []group
+		userAgent string
+		wantNil   bool
+		wantDelay time.Duration
+	}{
+		{
+			name:      "prefers named group over wildcard, regardless of order",
+			groups:    []group{wildcard, named},
+			userAgent: "linkscraper",
+			wantDelay: 5 * time.Second,
+		},
+		{
+			name:      "falls back to wildcard when no group names the agent",
+			groups:    []group{wildcard},
+			userAgent: "linkscraper",
+			wantDelay: 100 * time.Millisecond,
+		},
+		{
+			name:      "no match returns nil",
+			groups:    []group{named},
+			userAgent: "other",
+			wantNil:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectGroup(tt.groups, tt.userAgent)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("selectGroup() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("selectGroup() = nil, want non-nil")
+			}
+			if got.crawlDelay != tt.wantDelay {
+				t.Fatalf("selectGroup().crawlDelay = %v, want %v", got.crawlDelay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+// TestCacheCrawlDelayPrefersNamedGroup guards against Cache.fetch picking
+// whichever matching group happens to come last in the robots.txt file
+// instead of the one most specific to its configured User-Agent.
+func TestCacheCrawlDelayPrefersNamedGroup(t *testing.T) {
+	const robotsTxt = "User-agent: linkscraper\nCrawl-delay: 5\n\nUser-agent: *\nCrawl-delay: 0.1\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(robotsTxt))
+	}))
+	defer server.Close()
+
+	cache := NewCache("linkscraper")
+	target, err := url.Parse(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("url.Parse() error: %v", err)
+	}
+
+	h := cache.hostFor(target)
+	if h.crawlDelay != 5*time.Second {
+		t.Fatalf("crawlDelay = %v, want %v", h.crawlDelay, 5*time.Second)
+	}
+}
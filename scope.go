@@ -0,0 +1,67 @@
+package linkscraper
+
+import (
+	"golang.org/x/net/publicsuffix"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Scope decides whether a discovered link falls within the site a
+// LinkChecker is crawling. root is the crawl's start URL, target is the
+// URL being considered.
+type Scope interface {
+	InScope(root, target *url.URL) bool
+}
+
+// SameHostScope keeps links in scope when their host exactly matches the
+// start URL's host. This is the default Scope.
+type SameHostScope struct{}
+
+func (SameHostScope) InScope(root, target *url.URL) bool {
+	return root.Host == target.Host
+}
+
+// SameDomainScope keeps links in scope when they share the same
+// registrable domain (eTLD+1) as the start URL, regardless of subdomain.
+type SameDomainScope struct{}
+
+func (SameDomainScope) InScope(root, target *url.URL) bool {
+	rootDomain, err := publicsuffix.EffectiveTLDPlusOne(root.Hostname())
+	if err != nil {
+		return root.Host == target.Host
+	}
+	targetDomain, err := publicsuffix.EffectiveTLDPlusOne(target.Hostname())
+	if err != nil {
+		return false
+	}
+	return rootDomain == targetDomain
+}
+
+// RegexpScope keeps links in scope when their full URL matches Pattern.
+type RegexpScope struct {
+	Pattern *regexp.Regexp
+}
+
+func (s RegexpScope) InScope(root, target *url.URL) bool {
+	return s.Pattern.MatchString(target.String())
+}
+
+// DepthScope keeps links in scope when they are on the start URL's host
+// and their path is no more than Max directories deep, e.g. Max == 1
+// allows "/foo" and "/foo/bar" but not "/foo/bar/baz" or any off-site URL.
+type DepthScope struct {
+	Max int
+}
+
+func (s DepthScope) InScope(root, target *url.URL) bool {
+	if root.Host != target.Host {
+		return false
+	}
+
+	trimmed := strings.Trim(target.Path, "/")
+	if trimmed == "" {
+		return true
+	}
+	return strings.Count(trimmed, "/") <= s.Max
+}
@@ -0,0 +1,45 @@
+package linkscraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error: %v", raw, err)
+	}
+	return u
+}
+
+// TestDepthScopeInScope locks in the depth semantics documented on
+// DepthScope: Max == 1 allows "/foo" and "/foo/bar" but not
+// "/foo/bar/baz", and never allows a different host regardless of depth.
+func TestDepthScopeInScope(t *testing.T) {
+	root := mustParseURL(t, "http://example.com/")
+
+	tests := []struct {
+		name   string
+		max    int
+		target string
+		want   bool
+	}{
+		{"root path is always in scope", 1, "http://example.com/", true},
+		{"one segment within Max", 1, "http://example.com/foo", true},
+		{"two segments at Max", 1, "http://example.com/foo/bar", true},
+		{"three segments beyond Max", 1, "http://example.com/foo/bar/baz", false},
+		{"different host is never in scope", 1, "http://other.com/foo", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := DepthScope{Max: tt.max}
+			target := mustParseURL(t, tt.target)
+			if got := scope.InScope(root, target); got != tt.want {
+				t.Errorf("InScope(%q) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package linkscraper
+
+import "sync"
+
+// VisitedStore tracks which URLs have already been claimed for processing
+// during a crawl, so cooperating workers -- potentially running in
+// separate processes sharing one Queue -- agree on what still needs
+// fetching.
+type VisitedStore interface {
+	// MarkVisited atomically records key (a canonical URL string) as
+	// visited, returning false if it was already marked, in which case
+	// the caller should skip processing it again.
+	MarkVisited(key string) (bool, error)
+}
+
+// InMemoryVisitedStore is the default, single-process VisitedStore.
+type InMemoryVisitedStore struct {
+	lock sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewInMemoryVisitedStore creates an empty InMemoryVisitedStore.
+func NewInMemoryVisitedStore() *InMemoryVisitedStore {
+	return &InMemoryVisitedStore{seen: make(map[string]struct{})}
+}
+
+func (s *InMemoryVisitedStore) MarkVisited(key string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.seen[key]; ok {
+		return false, nil
+	}
+	s.seen[key] = struct{}{}
+	return true, nil
+}